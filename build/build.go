@@ -95,6 +95,12 @@ func build(env, out string) error {
 					return e
 				}
 			}
+		case ".html":
+			e := util.StripEnvFile(path, dst, util.ActiveEnv(env, true))
+			if e != nil {
+				log.Println(e)
+				return e
+			}
 		case ".svg":
 			if strings.HasSuffix(info.Name(), ".min.svg") {
 				e := util.CopyFile(path, dst)
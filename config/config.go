@@ -21,8 +21,9 @@ type Config struct {
 	Routes       []Route           `json:"routes"`
 	NotFoundPage string            `json:"notFoundPage"`
 	BlackList    []string          `json:"blackList"`
-	ApiServer    string            `json:"apiServer"` //API server, e.g. "http://localhost:12300"
-	Envs         map[string]Config `json:"envs"`      //customized environments
+	ApiServer    string            `json:"apiServer"`  //API server, e.g. "http://localhost:12300"
+	JSONErrors   []string          `json:"jsonErrors"` //path prefixes that respond errors in JSON, matched by segments, e.g. "/api"
+	Envs         map[string]Config `json:"envs"`       //customized environments
 	Lang         struct {
 		Dir        string `json:"dir"`        //language resources location
 		Default    string `json:"default"`    //default language, e.g. 'zh-CN'
@@ -35,8 +36,9 @@ type Config struct {
 	Strs              map[string]map[string]string `json:"-"`
 }
 type Route struct {
-	Path string `json:"path"`
-	To   string `json:"to"`
+	Path       string `json:"path"`
+	To         string `json:"to"`
+	JSONErrors bool   `json:"jsonErrors"` //respond errors in JSON instead of HTML
 }
 
 const (
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type jsonError struct {
+	Error  string `json:"error"`
+	Status int    `json:"status"`
+}
+
+// isJSONErrors reports whether errors of r should be responded in JSON
+func (s *Server) isJSONErrors(r *http.Request) bool {
+	for _, prefix := range s.cfg.JSONErrors {
+		if matchPathPrefix(prefix, r.URL.Path) {
+			return true
+		}
+	}
+	route, ok := s.matchRoute(r.URL.Path)
+	return ok && route.JSONErrors
+}
+
+// matchPathPrefix matches prefix by path segments, e.g. "/api" matches "/api" and "/api/a", but not "/apidocs"
+func matchPathPrefix(prefix, path string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, code int, msg string) {
+	if !s.isJSONErrors(r) {
+		http.Error(w, msg, code)
+		return
+	}
+
+	//headers might be set for html already
+	w.Header().Del("Content-Encoding")
+	w.Header().Del("Last-Modified")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	e := json.NewEncoder(w).Encode(jsonError{
+		Error:  msg,
+		Status: code,
+	})
+	if e != nil {
+		log.Println(e)
+	}
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/StevenZack/gte/config"
+)
+
+func newTestServer(t *testing.T, isRunningMode bool) *Server {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"index.html":  `<p>index</p>`,
+		"404.html":    `<p>not found page</p>`,
+		"broken.html": `<p>{{.Nope}}</p>`,
+	} {
+		e := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+		if e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	cfg := config.Config{
+		Root:         dir,
+		NotFoundPage: "/404.html",
+		JSONErrors:   []string{"/v1"},
+		Routes: []config.Route{
+			{Path: "/api/:id", To: "/broken.html", JSONErrors: true},
+			{Path: "/items/:id", To: "/missing.html", JSONErrors: true},
+			{Path: "/pages/:id", To: "/broken.html"},
+			{Path: "/a/:id", To: "/missing.html", JSONErrors: true},
+			{Path: "/a/b", To: "/missing.html"},
+			{Path: "/c/d", To: "/missing.html"},
+			{Path: "/c/:id", To: "/missing.html", JSONErrors: true},
+		},
+	}
+	s, e := NewServer(cfg, isRunningMode)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return s
+}
+
+func serveTest(s *Server, path string, header map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func decodeJSONError(t *testing.T, w *httptest.ResponseRecorder, status int) jsonError {
+	if w.Code != status {
+		t.Fatal("status code is not ", status, ", but ", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatal("Content-Type is not application/json , but ", ct)
+	}
+	if ce := w.Header().Get("Content-Encoding"); ce != "" {
+		t.Fatal("Content-Encoding is not empty , but ", ce)
+	}
+	v := jsonError{}
+	e := json.Unmarshal(w.Body.Bytes(), &v)
+	if e != nil {
+		t.Fatal(e, w.Body.String())
+	}
+	if v.Status != status {
+		t.Fatal("status is not ", status, ", but ", v.Status)
+	}
+	return v
+}
+
+func TestJSONErrorsPrefix(t *testing.T) {
+	s := newTestServer(t, false)
+
+	for _, path := range []string{"/v1", "/v1/users", "/v1/users.json"} {
+		v := decodeJSONError(t, serveTest(s, path, nil), http.StatusNotFound)
+		if v.Error != "404 page not found" {
+			t.Error("error is not `404 page not found` , but ", v.Error)
+			return
+		}
+	}
+
+	for _, path := range []string{"/v1docs", "/v1.html", "/other"} {
+		w := serveTest(s, path, nil)
+		if w.Code != http.StatusNotFound {
+			t.Error("status code is not 404 , but ", w.Code)
+			return
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "text/html" {
+			t.Error("Content-Type of ", path, " is not text/html , but ", ct)
+			return
+		}
+		if w.Body.String() != `<p>not found page</p>` {
+			t.Error("body is not the NotFoundPage , but ", w.Body.String())
+			return
+		}
+	}
+}
+
+func TestJSONErrorsRoute(t *testing.T) {
+	s := newTestServer(t, false)
+
+	// NotFoundPage is skipped for JSON routes
+	v := decodeJSONError(t, serveTest(s, "/items/1", nil), http.StatusNotFound)
+	if v.Error != "404 page not found" {
+		t.Error("error is not `404 page not found` , but ", v.Error)
+		return
+	}
+
+	v = decodeJSONError(t, serveTest(s, "/api/1", nil), http.StatusInternalServerError)
+	if !strings.Contains(v.Error, "can't evaluate field Nope") {
+		t.Error("error is not the raw template error , but ", v.Error)
+		return
+	}
+
+	// not flagged
+	w := serveTest(s, "/pages/1", nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Error("status code is not 500 , but ", w.Code)
+		return
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Error("Content-Type is not text/plain , but ", ct)
+		return
+	}
+}
+
+func TestJSONErrorsOverlappingRoutes(t *testing.T) {
+	s := newTestServer(t, false)
+
+	// served by the flagged "/a/:id"
+	decodeJSONError(t, serveTest(s, "/a/1", nil), http.StatusNotFound)
+	// served by the unflagged "/a/b", which is the last match
+	w := serveTest(s, "/a/b", nil)
+	if ct := w.Header().Get("Content-Type"); ct != "text/html" {
+		t.Error("Content-Type of /a/b is not text/html , but ", ct)
+		return
+	}
+	if w.Body.String() != `<p>not found page</p>` {
+		t.Error("body is not the NotFoundPage , but ", w.Body.String())
+		return
+	}
+	// served by the flagged "/c/:id", which is the last match
+	decodeJSONError(t, serveTest(s, "/c/d", nil), http.StatusNotFound)
+}
+
+func TestJSONErrorsRunningMode(t *testing.T) {
+	s := newTestServer(t, true)
+
+	for _, header := range []map[string]string{nil, {"Accept-Encoding": "gzip"}} {
+		v := decodeJSONError(t, serveTest(s, "/api/1", header), http.StatusInternalServerError)
+		if v.Error != http.StatusText(http.StatusInternalServerError) {
+			t.Error("error is not generic , but ", v.Error)
+			return
+		}
+	}
+
+	v := decodeJSONError(t, serveTest(s, "/items/1", nil), http.StatusNotFound)
+	if v.Error != "404 page not found" {
+		t.Error("error is not `404 page not found` , but ", v.Error)
+		return
+	}
+}
+
+func TestServeGzip(t *testing.T) {
+	s := newTestServer(t, true)
+
+	w := serveTest(s, "/", map[string]string{"Accept-Encoding": "gzip"})
+	if w.Code != http.StatusOK {
+		t.Error("status code is not 200 , but ", w.Code)
+		return
+	}
+	if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Error("Content-Encoding is not gzip , but ", ce)
+		return
+	}
+	rd, e := gzip.NewReader(w.Body)
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	b, e := io.ReadAll(rd)
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if string(b) != `<p>index</p>` {
+		t.Error("body is not `<p>index</p>` , but ", string(b))
+		return
+	}
+}
+
+func TestMatchPathPrefix(t *testing.T) {
+	for _, c := range []struct {
+		prefix, path string
+		want         bool
+	}{
+		{"/api", "/api", true},
+		{"/api", "/api/a", true},
+		{"/api", "/apidocs", false},
+		{"/api", "/api.html", false},
+		{"/api/", "/api/a", true},
+		{"/api/", "/api", false},
+	} {
+		b := matchPathPrefix(c.prefix, c.path)
+		if b != c.want {
+			t.Error("matchPathPrefix(", c.prefix, ",", c.path, ") is not ", c.want, ", but ", b)
+			return
+		}
+	}
+}
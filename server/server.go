@@ -61,7 +61,7 @@ func NewServer(cfg config.Config, isRunningMode bool) (*Server, error) {
 	// precompile in production mode
 	if isRunningMode {
 		var e error
-		s.precompiledTemplates, e = util.ParseTemplates(s.cfg.Root, s.funcs)
+		s.precompiledTemplates, e = util.ParseTemplates(s.cfg.Root, s.activeEnv(), s.funcs)
 		if e != nil {
 			log.Println(e)
 			return nil, e
@@ -109,11 +109,10 @@ func (s *Server) serveRoute(route config.Route, w http.ResponseWriter, r *http.R
 		route.To = "/index.html"
 	}
 
-	for _, cfgRoute := range s.cfg.Routes {
-		if util.MatchRoute(cfgRoute.Path, r.URL.Path) {
-			route.Path = cfgRoute.Path
-			route.To = cfgRoute.To
-		}
+	//statusCode is set when serving the not found page, which must not be routed again
+	if cfgRoute, ok := s.matchRoute(r.URL.Path); ok && statusCode == 0 {
+		route.Path = cfgRoute.Path
+		route.To = cfgRoute.To
 	}
 
 	//lang
@@ -171,16 +170,16 @@ func (s *Server) serveRoute(route config.Route, w http.ResponseWriter, r *http.R
 	if s.isRunningMode {
 		t = s.precompiledTemplates
 	} else {
-		t, e = util.ParseTemplates(s.cfg.Root, s.funcs)
+		t, e = util.ParseTemplates(s.cfg.Root, s.activeEnv(), s.funcs)
 		if e != nil {
 			log.Println(e)
-			http.Error(w, e.Error(), http.StatusInternalServerError)
+			s.InternalError(w, r, e)
 			return
 		}
 		if t == nil {
 			log.Println("t == nil")
 			if statusCode == 404 {
-				http.NotFound(w, r)
+				s.writeError(w, r, http.StatusNotFound, "404 page not found")
 				return
 			}
 			s.NotFound(w, r)
@@ -198,36 +197,54 @@ func (s *Server) serveRoute(route config.Route, w http.ResponseWriter, r *http.R
 		}
 
 		log.Println(e)
-		http.Error(w, e.Error(), http.StatusInternalServerError)
+		s.InternalError(w, r, e)
 		return
 	}
 
-	//gzip
-
-	if statusCode > 0 {
-		w.WriteHeader(statusCode)
-	}
+	//gzip, buffered so that errors can still be responded before the headers are written
 	if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-		rw := gzip.NewWriter(w)
-		defer rw.Close()
+		zipped := new(bytes.Buffer)
+		rw := gzip.NewWriter(zipped)
 		rw.Name, e = url.PathUnescape(filepath.Base(route.To))
 		if e != nil {
 			log.Println(e)
-			http.Error(w, e.Error(), http.StatusInternalServerError)
+			s.InternalError(w, r, e)
 			return
 		}
 		_, e = io.Copy(rw, out)
 		if e != nil {
 			log.Println(e)
-			http.Error(w, e.Error(), http.StatusInternalServerError)
+			s.InternalError(w, r, e)
 			return
 		}
-		return
+		e = rw.Close()
+		if e != nil {
+			log.Println(e)
+			s.InternalError(w, r, e)
+			return
+		}
+		out = zipped
 	}
 
+	if statusCode > 0 {
+		w.WriteHeader(statusCode)
+	}
 	w.Write(out.Bytes())
 }
 
+// matchRoute returns the configured route serving path, the last one wins if several match
+func (s *Server) matchRoute(path string) (config.Route, bool) {
+	var route config.Route
+	ok := false
+	for _, cfgRoute := range s.cfg.Routes {
+		if util.MatchRoute(cfgRoute.Path, path) {
+			route = cfgRoute
+			ok = true
+		}
+	}
+	return route, ok
+}
+
 func (s *Server) ListenAndServe() error {
 	return s.HTTPServer.ListenAndServe()
 }
@@ -249,14 +266,28 @@ func (s *Server) AddPrehandler(fn func(w http.ResponseWriter, r *http.Request) b
 }
 
 func (s *Server) NotFound(w http.ResponseWriter, r *http.Request) {
-	if s.cfg.NotFoundPage != "" {
+	if s.cfg.NotFoundPage != "" && !s.isJSONErrors(r) {
 		s.serveRoute(config.Route{
 			Path: r.URL.Path,
 			To:   s.cfg.NotFoundPage,
 		}, w, r, 404)
 		return
 	}
-	http.NotFound(w, r)
+	s.writeError(w, r, http.StatusNotFound, "404 page not found")
+}
+
+// InternalError responds 500 error, the message is generic for JSON routes in production mode
+func (s *Server) InternalError(w http.ResponseWriter, r *http.Request, e error) {
+	msg := e.Error()
+	if s.isRunningMode && s.isJSONErrors(r) {
+		msg = http.StatusText(http.StatusInternalServerError)
+	}
+	s.writeError(w, r, http.StatusInternalServerError, msg)
+}
+
+// activeEnv returns the environment used for {{/* env:xxx */}} blocks
+func (s *Server) activeEnv() string {
+	return util.ActiveEnv(s.cfg.Env, s.isRunningMode)
 }
 
 func (s *Server) Reload() error {
@@ -269,7 +300,7 @@ func (s *Server) Reload() error {
 	s.cfg = cfg
 
 	if s.isRunningMode {
-		s.precompiledTemplates, e = util.ParseTemplates(s.cfg.Root, s.funcs)
+		s.precompiledTemplates, e = util.ParseTemplates(s.cfg.Root, s.activeEnv(), s.funcs)
 		if e != nil {
 			log.Println(e)
 			return e
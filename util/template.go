@@ -1,16 +1,128 @@
 package util
 
 import (
+	"errors"
 	"html/template"
 	"io"
 	"io/fs"
+	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/StevenZack/tools/strToolkit"
 )
 
-func ParseTemplates(dir string, funcs template.FuncMap) (*template.Template, error) {
+const (
+	envDev  = "dev"
+	envProd = "prod"
+
+	trimSpaces = " \t\r\n"
+)
+
+var (
+	envDirective = regexp.MustCompile(`{{(-[ \t\r\n])?/\*\s*(env:([\w.-]+)|endenv)\s*\*/([ \t\r\n]-)?}}`)
+	// any comment mentioning env directives, used to reject malformed ones
+	envComment = regexp.MustCompile(`(?s){{[^{}]*?/\*.*?(env:|endenv).*?\*/[^{}]*?}}`)
+)
+
+// ActiveEnv returns the environment used for {{/* env:xxx */}} blocks.
+// 'dev' for developing and 'prod' for production if env is not specified
+func ActiveEnv(env string, isRunningMode bool) string {
+	if env != "" {
+		return env
+	}
+	if isRunningMode {
+		return envProd
+	}
+	return envDev
+}
+
+// StripEnvBlocks removes {{/* env:xxx */}} ... {{/* endenv */}} blocks whose environment is not env.
+// Directives of matched blocks are removed as well, and nesting is not allowed.
+// Trim markers like {{- /* env:xxx */ -}} trim the adjacent whitespace just like template comments do.
+// Comments mentioning env:/endenv that aren't valid directives are rejected, so that blocks never leak as plain comments.
+func StripEnvBlocks(src, env string) (string, error) {
+	matches := envDirective.FindAllStringSubmatchIndex(src, -1)
+	valid := make(map[int]bool)
+	for _, m := range matches {
+		valid[m[0]] = true
+	}
+	for _, m := range envComment.FindAllStringIndex(src, -1) {
+		if !valid[m[0]] {
+			return "", errors.New("Malformed env directive " + src[m[0]:m[1]] + ", e.g. {{/* env:dev */}}")
+		}
+	}
+
+	out := new(strings.Builder)
+	last := 0
+	blockEnv := ""
+	inBlock := false
+	trimLeft := false
+	for _, m := range matches {
+		text := src[last:m[0]]
+		if trimLeft {
+			text = strings.TrimLeft(text, trimSpaces)
+		}
+		if m[2] != -1 {
+			text = strings.TrimRight(text, trimSpaces)
+		}
+
+		directive := src[m[4]:m[5]]
+		if directive == "endenv" {
+			if !inBlock {
+				return "", errors.New("Unexpected {{/* endenv */}} without {{/* env:xxx */}}")
+			}
+			if blockEnv == env {
+				out.WriteString(text)
+			}
+			inBlock = false
+		} else {
+			if inBlock {
+				return "", errors.New("Nested {{/* " + directive + " */}} inside {{/* env:" + blockEnv + " */}} is not allowed")
+			}
+			out.WriteString(text)
+			blockEnv = src[m[6]:m[7]]
+			inBlock = true
+		}
+		trimLeft = m[8] != -1
+		last = m[1]
+	}
+	if inBlock {
+		return "", errors.New("{{/* env:" + blockEnv + " */}} is not closed by {{/* endenv */}}")
+	}
+	text := src[last:]
+	if trimLeft {
+		text = strings.TrimLeft(text, trimSpaces)
+	}
+	out.WriteString(text)
+	return out.String(), nil
+}
+
+// StripEnvFile writes file to dst with env blocks that don't belong to env removed
+func StripEnvFile(file, dst, env string) error {
+	b, e := ioutil.ReadFile(file)
+	if e != nil {
+		log.Println(e)
+		return e
+	}
+	text, e := StripEnvBlocks(string(b), env)
+	if e != nil {
+		log.Println(e)
+		return errors.New(file + ": " + e.Error())
+	}
+	e = ioutil.WriteFile(dst, []byte(text), 0644)
+	if e != nil {
+		log.Println(e)
+		return e
+	}
+	return nil
+}
+
+// ParseTemplates parses all .html files under dir, stripping env blocks that don't belong to env
+func ParseTemplates(dir, env string, funcs template.FuncMap) (*template.Template, error) {
 	abs, e := filepath.Abs(dir)
 	if e != nil {
 		return nil, e
@@ -46,7 +158,12 @@ func ParseTemplates(dir string, funcs template.FuncMap) (*template.Template, err
 				return e
 			}
 
-			_, e = t.Parse(string(b))
+			text, e := StripEnvBlocks(string(b), env)
+			if e != nil {
+				return errors.New(relativeUri + ": " + e.Error())
+			}
+
+			_, e = t.Parse(text)
 			if e != nil {
 				return e
 			}
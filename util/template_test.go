@@ -0,0 +1,197 @@
+package util
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStripEnvBlocks(t *testing.T) {
+	src := `a{{/* env:dev */}}b{{/* endenv */}}c{{- /* env:prod */ -}}d{{/* endenv */}}e`
+	s, e := StripEnvBlocks(src, "dev")
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if s != `abce` {
+		t.Error("s is not `abce` , but ", s)
+		return
+	}
+
+	s, e = StripEnvBlocks(src, "prod")
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if s != `acde` {
+		t.Error("s is not `acde` , but ", s)
+		return
+	}
+
+	s, e = StripEnvBlocks(src, "")
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if s != `ace` {
+		t.Error("s is not `ace` , but ", s)
+		return
+	}
+
+	s, e = StripEnvBlocks(`a{{/* normal comment */}}b`, "dev")
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if s != `a{{/* normal comment */}}b` {
+		t.Error("s is not `a{{/* normal comment */}}b` , but ", s)
+		return
+	}
+}
+
+func TestStripEnvBlocksTrim(t *testing.T) {
+	s, e := StripEnvBlocks("x\n{{- /* env:dev */ -}}\ny{{/* endenv */}}", "dev")
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if s != "xy" {
+		t.Errorf("s is not `xy` , but %q", s)
+		return
+	}
+
+	src := "<p>\n\t{{- /* env:dev */}}\n\tprofiler\n\t{{- /* endenv */ -}}\n</p>"
+	s, e = StripEnvBlocks(src, "dev")
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if s != "<p>\n\tprofiler</p>" {
+		t.Errorf("s is not `<p>\\n\\tprofiler</p>` , but %q", s)
+		return
+	}
+
+	s, e = StripEnvBlocks(src, "prod")
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if s != "<p></p>" {
+		t.Errorf("s is not `<p></p>` , but %q", s)
+		return
+	}
+
+	s, e = StripEnvBlocks("a \n{{/* env:dev */}} b {{/* endenv */}}\n c", "dev")
+	if e != nil {
+		t.Error(e)
+		return
+	}
+	if s != "a \n b \n c" {
+		t.Errorf("s is not `a \\n b \\n c` , but %q", s)
+		return
+	}
+}
+
+func TestStripEnvBlocksTrimSpaces(t *testing.T) {
+	for _, src := range []string{
+		"a{{-\n/* env:dev */\n-}}SECRET{{-\n/* endenv */\n-}}b",
+		"a{{-\t/* env:dev */\t-}}SECRET{{-\t/* endenv */\t-}}b",
+		"a{{-\r/* env:dev */\r-}}SECRET{{-\r/* endenv */\r-}}b",
+	} {
+		s, e := StripEnvBlocks(src, "prod")
+		if e != nil {
+			t.Error(e)
+			return
+		}
+		if s != "ab" {
+			t.Errorf("s is not `ab` for %q , but %q", src, s)
+			return
+		}
+		if strings.Contains(s, "SECRET") || strings.Contains(s, "env:") {
+			t.Errorf("block content remains in prod output for %q: %q", src, s)
+			return
+		}
+
+		s, e = StripEnvBlocks(src, "dev")
+		if e != nil {
+			t.Error(e)
+			return
+		}
+		if s != "aSECRETb" {
+			t.Errorf("s is not `aSECRETb` for %q , but %q", src, s)
+			return
+		}
+	}
+}
+
+func TestStripEnvBlocksInvalid(t *testing.T) {
+	for _, src := range []string{
+		`a{{/* env:dev */}}b`,
+		`a{{/* endenv */}}b`,
+		`{{/* env:dev */}}{{/* env:prod */}}{{/* endenv */}}{{/* endenv */}}`,
+		"a{{-  /* env:dev */}}b{{/* endenv */}}",
+		"a{{-\r\n/* env:dev */\r\n-}}b{{/* endenv */}}",
+		"a{{/* env:dev */\n\n-}}b{{/* endenv */}}",
+		"a{{ /* env:dev */ }}b{{/* endenv */}}",
+		"a{{/* env: dev */}}b{{/* endenv */}}",
+		"a{{/* env:dev */}}b{{/* endenv -- done */}}",
+	} {
+		_, e := StripEnvBlocks(src, "dev")
+		if e == nil {
+			t.Error("e is nil for ", src)
+			return
+		}
+	}
+}
+
+func TestParseTemplatesEnv(t *testing.T) {
+	dir := t.TempDir()
+	e := os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<p>{{/* env:dev */}}{{.}}{{/* endenv */}}</p>`), 0644)
+	if e != nil {
+		t.Error(e)
+		return
+	}
+
+	for env, want := range map[string]string{
+		"dev":  `<p>profiler</p>`,
+		"prod": `<p></p>`,
+	} {
+		tpl, e := ParseTemplates(dir, env, template.FuncMap{})
+		if e != nil {
+			t.Error(e)
+			return
+		}
+		out := new(bytes.Buffer)
+		e = tpl.ExecuteTemplate(out, "/index.html", "profiler")
+		if e != nil {
+			t.Error(e)
+			return
+		}
+		if out.String() != want {
+			t.Error("output is not `"+want+"` in env '"+env+"', but ", out.String())
+			return
+		}
+	}
+}
+
+func TestActiveEnv(t *testing.T) {
+	for _, c := range []struct {
+		env           string
+		isRunningMode bool
+		want          string
+	}{
+		{"", false, "dev"},
+		{"", true, "prod"},
+		{"staging", false, "staging"},
+		{"staging", true, "staging"},
+	} {
+		s := ActiveEnv(c.env, c.isRunningMode)
+		if s != c.want {
+			t.Error("s is not `"+c.want+"` , but ", s)
+			return
+		}
+	}
+}